@@ -2,18 +2,37 @@ package airgo
 
 import (
 	"bufio"
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/XrayR-project/XrayR/api"
+	"github.com/XrayR-project/XrayR/panel/registry"
 	"github.com/go-resty/resty/v2"
 )
 
+// closeWaitTimeout bounds how long Close waits for in-flight requests to
+// finish after cancelling the client's parent context.
+const closeWaitTimeout = 10 * time.Second
+
+// apiType is the value operators set as api.Config.APIType to select this
+// backend.
+const apiType = "AirGo"
+
+func init() {
+	registry.Register(apiType, func(c *api.Config) (api.Client, error) {
+		return New(c), nil
+	})
+}
+
 type APIClient struct {
 	client        *resty.Client
 	APIHost       string
@@ -26,6 +45,24 @@ type APIClient struct {
 	DeviceLimit   int
 	LocalRuleList []api.DetectRule
 	eTags         map[string]string
+
+	// privKeyIdx rotates through NodeInfoResponse.PrivateKeyPool so
+	// successive GetNodeInfo polls hand out different keys, staggering
+	// REALITY X25519 rotation instead of swapping every client at once.
+	privKeyIdx uint32
+
+	// ctx is the parent of every per-call context derived via Context().
+	// Close cancels it so a hung panel can't stall requests forever, and
+	// waits on inFlight for outstanding calls to unwind.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inFlight sync.WaitGroup
+
+	// reporter batches/retries/spools ReportUserTraffic and
+	// ReportNodeOnlineUsers calls; see TrafficReporter.
+	reporter  *TrafficReporter
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // 改进的Show函数，处理可能的序列化错误
@@ -51,7 +88,8 @@ func New(apiConfig *api.Config) *APIClient {
 		})
 
 	localRuleList := readLocalRuleList(apiConfig.RuleListPath)
-	return &APIClient{
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &APIClient{
 		client:        client,
 		NodeID:        apiConfig.NodeID,
 		Key:           apiConfig.Key,
@@ -63,7 +101,44 @@ func New(apiConfig *api.Config) *APIClient {
 		DeviceLimit:   apiConfig.DeviceLimit,
 		LocalRuleList: localRuleList,
 		eTags:         make(map[string]string),
+		ctx:           ctx,
+		cancel:        cancel,
 	}
+	c.reporter = NewTrafficReporter(c, apiConfig)
+	return c
+}
+
+// Context returns the client's parent context. Callers should derive a
+// per-call context from it - e.g. context.WithTimeout(c.Context(),
+// pollInterval) - so a Close mid-flight cancels outstanding requests
+// instead of leaving them to time out on their own.
+func (c *APIClient) Context() context.Context {
+	return c.ctx
+}
+
+// Close stops the TrafficReporter (giving it one last flush attempt),
+// then cancels the client's parent context and waits up to
+// closeWaitTimeout for any still in-flight request to return, so a
+// shutdown doesn't leave the reporting goroutine blocked on a hung
+// panel.
+func (c *APIClient) Close() error {
+	c.closeOnce.Do(func() {
+		done := make(chan struct{})
+		go func() {
+			c.reporter.Close()
+			c.cancel()
+			c.inFlight.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(closeWaitTimeout):
+			c.cancel()
+			c.closeErr = fmt.Errorf("airgo: timed out after %s waiting for in-flight requests", closeWaitTimeout)
+		}
+	})
+	return c.closeErr
 }
 
 // 改进的readLocalRuleList函数，增加了文件打开的nil判断
@@ -104,9 +179,13 @@ func updateETag(c *APIClient, res *resty.Response, key string) {
 }
 
 // 在GetNodeInfo和GetUserList中使用updateETag
-func (c *APIClient) GetNodeInfo() (*api.NodeInfo, error) {
+func (c *APIClient) GetNodeInfo(ctx context.Context) (*api.NodeInfo, error) {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
 	path := "/api/public/airgo/node/getNodeInfo"
 	res, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"id": fmt.Sprintf("%d", c.NodeID),
 		}).
@@ -114,6 +193,10 @@ func (c *APIClient) GetNodeInfo() (*api.NodeInfo, error) {
 		ForceContentType("application/json").
 		Get(path)
 
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node info: %w", err)
+	}
+
 	if res.StatusCode() == 304 {
 		return nil, errors.New(api.NodeNotModified)
 	}
@@ -134,9 +217,13 @@ func (c *APIClient) GetNodeInfo() (*api.NodeInfo, error) {
 	return nodeInfo, nil
 }
 
-func (c *APIClient) GetUserList() (*[]api.UserInfo, error) {
+func (c *APIClient) GetUserList(ctx context.Context) (*[]api.UserInfo, error) {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
 	path := "/api/public/airgo/user/getUserlist"
 	res, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"id": fmt.Sprintf("%d", c.NodeID),
 		}).
@@ -182,11 +269,85 @@ func calculateSpeedLimit(nodeSpeedLimit int64, defaultSpeedLimit float64) uint64
 	return uint64((defaultSpeedLimit * 1000000) / 8)
 }
 
-func (c *APIClient) GetNodeRule() (*[]api.DetectRule, error) {
+func (c *APIClient) GetNodeRule(ctx context.Context) (*[]api.DetectRule, error) {
 	ruleList := c.LocalRuleList
 	return &ruleList, nil
 }
 
+// GetUserRules fetches per-user routing directives - domain/IP
+// blocklists and forced outbound tags - from AirGo, so the controller
+// can scope Xray routing rules by user email/UUID instead of applying
+// GetNodeRule's node-wide detect rules to everyone.
+func (c *APIClient) GetUserRules(ctx context.Context) (*[]api.UserRule, error) {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
+	path := "/api/public/airgo/user/getUserRules"
+	res, err := c.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"id": fmt.Sprintf("%d", c.NodeID),
+		}).
+		SetHeader("If-None-Match", c.eTags["userrules"]).
+		ForceContentType("application/json").
+		Get(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user rules: %w", err)
+	}
+
+	if res.StatusCode() == 304 {
+		return nil, errors.New(api.RuleNotModified)
+	}
+
+	updateETag(c, res, "userrules")
+
+	var ruleResponse []UserRuleResponse
+	if err = json.Unmarshal(res.Body(), &ruleResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user rules response: %w", err)
+	}
+
+	userRules := make([]api.UserRule, len(ruleResponse))
+	for i, v := range ruleResponse {
+		userRules[i] = api.UserRule{
+			Email:       v.Email,
+			UUID:        v.UUID,
+			DomainList:  v.DomainList,
+			IPList:      v.IPList,
+			OutboundTag: v.OutboundTag,
+		}
+	}
+
+	return &userRules, nil
+}
+
+// nextPoolPrivateKey returns successive keys from pool on each call, so a
+// multi-key rotation lands on a different key per poll instead of
+// flipping every active client over to a new key at once.
+func (c *APIClient) nextPoolPrivateKey(pool []string) string {
+	idx := atomic.AddUint32(&c.privKeyIdx, 1) - 1
+	return pool[int(idx)%len(pool)]
+}
+
+// validateShortIds checks that every REALITY short ID is a hex string of
+// even length no longer than 16 characters, per the REALITY spec. An
+// empty string is always valid: it's how REALITY represents "no short
+// ID" for clients that don't send one.
+func validateShortIds(ids []string) error {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if len(id) > 16 || len(id)%2 != 0 {
+			return fmt.Errorf("short id %q must be hex with an even length of at most 16", id)
+		}
+		if _, err := hex.DecodeString(id); err != nil {
+			return fmt.Errorf("short id %q is not valid hex: %w", id, err)
+		}
+	}
+	return nil
+}
+
 func (c *APIClient) ParseAirGoNodeInfo(n *NodeInfoResponse) (*api.NodeInfo, error) {
 	var nodeInfo api.NodeInfo
 	var speedLimit uint64
@@ -206,15 +367,34 @@ func (c *APIClient) ParseAirGoNodeInfo(n *NodeInfoResponse) (*api.NodeInfo, erro
 	}
 	if n.Security == "reality" {
 		enableREALITY = true
+
+		serverNames := n.ServerNames
+		if len(serverNames) == 0 {
+			serverNames = []string{n.Sni}
+		}
+
+		shortIds := n.ShortIds
+		if len(shortIds) == 0 {
+			shortIds = []string{"", "0123456789abcdef"}
+		}
+		if err := validateShortIds(shortIds); err != nil {
+			return nil, fmt.Errorf("invalid REALITY short ids: %w", err)
+		}
+
+		privateKey := n.PrivateKey
+		if len(n.PrivateKeyPool) > 0 {
+			privateKey = c.nextPoolPrivateKey(n.PrivateKeyPool)
+		}
+
 		realityConfig = &api.REALITYConfig{
 			Dest:             n.Dest,
 			ProxyProtocolVer: 0,
-			ServerNames:      []string{n.Sni},
-			PrivateKey:       n.PrivateKey,
-			MinClientVer:     "",
-			MaxClientVer:     "",
-			MaxTimeDiff:      0,
-			ShortIds:         []string{"", "0123456789abcdef"},
+			ServerNames:      serverNames,
+			PrivateKey:       privateKey,
+			MinClientVer:     n.MinClientVer,
+			MaxClientVer:     n.MaxClientVer,
+			MaxTimeDiff:      n.MaxTimeDiff,
+			ShortIds:         shortIds,
 		}
 	}
 
@@ -295,6 +475,46 @@ func (c *APIClient) ParseAirGoNodeInfo(n *NodeInfoResponse) (*api.NodeInfo, erro
 				nodeInfo.Header = header
 			}
 		}
+	case "hysteria2", "Hysteria2":
+		h = map[string]any{
+			"upMbps":            n.UpMbps,
+			"downMbps":          n.DownMbps,
+			"congestionControl": n.CongestionControl,
+		}
+		if n.ObfsPassword != "" {
+			h["obfs"] = map[string]any{
+				"type":     "salamander",
+				"password": n.ObfsPassword,
+			}
+		}
+		header, _ = json.Marshal(h)
+		nodeInfo = api.NodeInfo{
+			NodeType:          c.NodeType,
+			NodeID:            c.NodeID,
+			Port:              uint32(n.Port),
+			SpeedLimit:        speedLimit,
+			TransportProtocol: "hysteria2",
+			EnableTLS:         true,
+			Host:              n.Host,
+			Header:            header,
+		}
+	case "tuic", "TUIC":
+		h = map[string]any{
+			"uuid":              n.TuicUUID,
+			"password":          n.TuicPassword,
+			"congestionControl": n.CongestionControl,
+		}
+		header, _ = json.Marshal(h)
+		nodeInfo = api.NodeInfo{
+			NodeType:          c.NodeType,
+			NodeID:            c.NodeID,
+			Port:              uint32(n.Port),
+			SpeedLimit:        speedLimit,
+			TransportProtocol: "tuic",
+			EnableTLS:         true,
+			Host:              n.Host,
+			Header:            header,
+		}
 	case "Shadowsocks", "shadowsocks":
 		nodeInfo = api.NodeInfo{
 			NodeType:          c.NodeType,
@@ -326,7 +546,7 @@ func (c *APIClient) ParseAirGoNodeInfo(n *NodeInfoResponse) (*api.NodeInfo, erro
 	return &nodeInfo, nil
 }
 
-func (c *APIClient) ReportNodeStatus(nodeStatus *api.NodeStatus) error {
+func (c *APIClient) ReportNodeStatus(ctx context.Context, nodeStatus *api.NodeStatus) error {
 	path := "/api/public/airgo/node/reportNodeStatus"
 	nodeStatusRequest := NodeStatusRequest{
 		ID:     c.NodeID,
@@ -336,20 +556,42 @@ func (c *APIClient) ReportNodeStatus(nodeStatus *api.NodeStatus) error {
 		Uptime: nodeStatus.Uptime,
 	}
 
-	return c.postRequest(path, nodeStatusRequest)
+	return c.postRequest(ctx, path, nodeStatusRequest)
 }
 
-func (c *APIClient) ReportUserTraffic(userTraffic *[]api.UserTraffic) error {
+// ReportUserTraffic hands userTraffic to the client's TrafficReporter
+// instead of posting it directly, so a transient panel outage retries
+// and spools instead of silently losing the batch. See sendUserTraffic
+// for the actual POST the reporter eventually makes.
+func (c *APIClient) ReportUserTraffic(ctx context.Context, userTraffic *[]api.UserTraffic) error {
+	c.reporter.QueueUserTraffic(*userTraffic)
+	return nil
+}
+
+// ReportNodeOnlineUsers hands onlineUserList to the client's
+// TrafficReporter; see ReportUserTraffic.
+func (c *APIClient) ReportNodeOnlineUsers(ctx context.Context, onlineUserList *[]api.OnlineUser) error {
+	c.reporter.QueueOnlineUsers(*onlineUserList)
+	return nil
+}
+
+// sendUserTraffic performs the actual reportUserTraffic POST. It is
+// called only from TrafficReporter's flush loop, never directly by
+// ReportUserTraffic, so every traffic report goes through the
+// batch/retry/spool path.
+func (c *APIClient) sendUserTraffic(ctx context.Context, userTraffic *[]api.UserTraffic) error {
 	path := "/api/public/airgo/user/reportUserTraffic"
 	userTrafficRequest := UserTrafficRequest{
 		ID:          c.NodeID,
 		UserTraffic: *userTraffic,
 	}
 
-	return c.postRequest(path, userTrafficRequest)
+	return c.postRequest(ctx, path, userTrafficRequest)
 }
 
-func (c *APIClient) ReportNodeOnlineUsers(onlineUserList *[]api.OnlineUser) error {
+// sendNodeOnlineUsers performs the actual AGReportNodeOnlineUsers POST;
+// see sendUserTraffic.
+func (c *APIClient) sendNodeOnlineUsers(ctx context.Context, onlineUserList *[]api.OnlineUser) error {
 	onlineUser := OnlineUser{
 		NodeID:      c.NodeID,
 		UserNodeMap: make(map[int][]string),
@@ -360,10 +602,14 @@ func (c *APIClient) ReportNodeOnlineUsers(onlineUserList *[]api.OnlineUser) erro
 	}
 
 	path := "/api/public/airgo/user/AGReportNodeOnlineUsers"
-	return c.postRequest(path, onlineUser)
+	return c.postRequest(ctx, path, onlineUser)
 }
-func (c *APIClient) postRequest(path string, body any) error {
+func (c *APIClient) postRequest(ctx context.Context, path string, body any) error {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
 	res, err := c.client.R().
+		SetContext(ctx).
 		SetBody(body).
 		ForceContentType("application/json").
 		Post(path)
@@ -383,7 +629,19 @@ func (c *APIClient) Describe() api.ClientInfo {
 	return api.ClientInfo{}
 }
 
-func (c *APIClient) ReportIllegal(detectResultList *[]api.DetectResult) (err error) {
+// Capabilities reports that AirGo's REST surface backs everything in
+// api.Client except illegal traffic reporting, which it doesn't expose
+// an endpoint for yet (see ReportIllegal).
+func (c *APIClient) Capabilities() api.Capabilities {
+	return api.Capabilities{
+		SupportsOnlineUserReporting: true,
+		SupportsIllegalReporting:    false,
+		SupportsStreaming:           false,
+		SupportsUserRules:           true,
+	}
+}
+
+func (c *APIClient) ReportIllegal(ctx context.Context, detectResultList *[]api.DetectResult) (err error) {
 	return nil
 }
 func (c *APIClient) Debug() {}
@@ -0,0 +1,124 @@
+package airgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/XrayR-project/XrayR/api"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *APIClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := New(&api.Config{
+		APIHost:  server.URL,
+		Key:      "test-key",
+		NodeID:   1,
+		NodeType: "Hysteria2",
+		Timeout:  5,
+	})
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestGetNodeInfo_Hysteria2(t *testing.T) {
+	resp := NodeInfoResponse{
+		Protocol:          "hysteria2",
+		Port:              8443,
+		Host:              "example.com",
+		ObfsPassword:      "obfs-secret",
+		UpMbps:            100,
+		DownMbps:          200,
+		CongestionControl: "bbr",
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	nodeInfo, err := c.GetNodeInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodeInfo returned error: %v", err)
+	}
+	if nodeInfo.TransportProtocol != "hysteria2" {
+		t.Fatalf("expected transport protocol hysteria2, got %q", nodeInfo.TransportProtocol)
+	}
+	if nodeInfo.Port != 8443 {
+		t.Fatalf("expected port 8443, got %d", nodeInfo.Port)
+	}
+
+	var header map[string]any
+	if err := json.Unmarshal(nodeInfo.Header, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+	if header["upMbps"].(float64) != 100 {
+		t.Fatalf("expected upMbps 100, got %v", header["upMbps"])
+	}
+	if _, ok := header["obfs"]; !ok {
+		t.Fatalf("expected obfs block when ObfsPassword is set, got %v", header)
+	}
+}
+
+func TestGetNodeInfo_Hysteria2_NoObfs(t *testing.T) {
+	resp := NodeInfoResponse{
+		Protocol:          "hysteria2",
+		Port:              8443,
+		Host:              "example.com",
+		UpMbps:            100,
+		DownMbps:          200,
+		CongestionControl: "bbr",
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	nodeInfo, err := c.GetNodeInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodeInfo returned error: %v", err)
+	}
+
+	var header map[string]any
+	if err := json.Unmarshal(nodeInfo.Header, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+	if _, ok := header["obfs"]; ok {
+		t.Fatalf("expected no obfs block when ObfsPassword is empty, got %v", header)
+	}
+}
+
+func TestGetNodeInfo_TUIC(t *testing.T) {
+	resp := NodeInfoResponse{
+		Protocol:          "tuic",
+		Port:              8444,
+		Host:              "example.com",
+		TuicUUID:          "11111111-1111-1111-1111-111111111111",
+		TuicPassword:      "tuic-secret",
+		CongestionControl: "cubic",
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	nodeInfo, err := c.GetNodeInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodeInfo returned error: %v", err)
+	}
+	if nodeInfo.TransportProtocol != "tuic" {
+		t.Fatalf("expected transport protocol tuic, got %q", nodeInfo.TransportProtocol)
+	}
+
+	var header map[string]any
+	if err := json.Unmarshal(nodeInfo.Header, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+	if header["uuid"] != resp.TuicUUID {
+		t.Fatalf("expected uuid %q, got %v", resp.TuicUUID, header["uuid"])
+	}
+}
@@ -0,0 +1,38 @@
+package airgo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetNodeInfo_RespectsCallerDeadline(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetNodeInfo(ctx); err == nil {
+		t.Fatalf("expected GetNodeInfo to return an error once its context deadline passed")
+	}
+}
+
+func TestClose_CancelsParentContext(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	select {
+	case <-c.Context().Done():
+	default:
+		t.Fatalf("expected Close to cancel the client's parent context")
+	}
+}
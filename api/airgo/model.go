@@ -0,0 +1,89 @@
+package airgo
+
+import "github.com/XrayR-project/XrayR/api"
+
+// NodeInfoResponse is the wire shape returned by
+// /api/public/airgo/node/getNodeInfo.
+type NodeInfoResponse struct {
+	ID             int     `json:"id"`
+	Protocol       string  `json:"protocol"`
+	Port           int     `json:"port"`
+	Network        string  `json:"network"`
+	Type           string  `json:"type"`
+	Host           string  `json:"host"`
+	Path           string  `json:"path"`
+	ServiceName    string  `json:"serviceName"`
+	Scy            string  `json:"scy"`
+	VlessFlow      string  `json:"vlessFlow"`
+	Security       string  `json:"security"`
+	Dest           string  `json:"dest"`
+	ServerKey      string  `json:"serverKey"`
+
+	// Sni and PrivateKey are the single-value REALITY fields this panel
+	// has always sent. ServerNames/PrivateKeyPool below are additive:
+	// when present they take precedence, so older AirGo panels that
+	// only send Sni/PrivateKey keep working unchanged.
+	Sni            string  `json:"sni"`
+	PrivateKey     string  `json:"privateKey"`
+	NodeSpeedLimit float64 `json:"nodeSpeedLimit"`
+
+	// Hysteria2 / TUIC extensions.
+	ObfsPassword      string `json:"obfsPassword"`
+	UpMbps            int    `json:"upMbps"`
+	DownMbps          int    `json:"downMbps"`
+	CongestionControl string `json:"congestionControl"`
+	TuicUUID          string `json:"tuicUuid"`
+	TuicPassword      string `json:"tuicPassword"`
+
+	// REALITY multi-value extensions. ServerNames/ShortIds replace the
+	// old single Sni/hard-coded short ID list; PrivateKeyPool lets the
+	// panel push several keys for staggered X25519 rotation.
+	ServerNames    []string `json:"serverNames,omitempty"`
+	ShortIds       []string `json:"shortIds,omitempty"`
+	MinClientVer   string   `json:"minClientVer,omitempty"`
+	MaxClientVer   string   `json:"maxClientVer,omitempty"`
+	MaxTimeDiff    uint64   `json:"maxTimeDiff,omitempty"`
+	PrivateKeyPool []string `json:"privateKeyPool,omitempty"`
+}
+
+// UserResponse is the wire shape of a single entry returned by
+// /api/public/airgo/user/getUserlist.
+type UserResponse struct {
+	ID             int64  `json:"id"`
+	UUID           string `json:"uuid"`
+	UserName       string `json:"userName"`
+	Passwd         string `json:"passwd"`
+	NodeSpeedLimit int64  `json:"nodeSpeedLimit"`
+	NodeConnector  int64  `json:"nodeConnector"`
+}
+
+// NodeStatusRequest is posted to /api/public/airgo/node/reportNodeStatus.
+type NodeStatusRequest struct {
+	ID     int     `json:"id"`
+	CPU    float64 `json:"cpu"`
+	Mem    float64 `json:"mem"`
+	Disk   float64 `json:"disk"`
+	Uptime uint64  `json:"uptime"`
+}
+
+// UserTrafficRequest is posted to /api/public/airgo/user/reportUserTraffic.
+type UserTrafficRequest struct {
+	ID          int               `json:"id"`
+	UserTraffic []api.UserTraffic `json:"userTraffic"`
+}
+
+// UserRuleResponse is a single entry returned by
+// /api/public/airgo/user/getUserRules.
+type UserRuleResponse struct {
+	UUID        string   `json:"uuid"`
+	Email       string   `json:"email"`
+	DomainList  []string `json:"domainList"`
+	IPList      []string `json:"ipList"`
+	OutboundTag string   `json:"outboundTag"`
+}
+
+// OnlineUser is posted to /api/public/airgo/user/AGReportNodeOnlineUsers.
+type OnlineUser struct {
+	NodeID      int              `json:"nodeId"`
+	UserNodeMap map[int][]string `json:"userNodeMap"`
+}
@@ -0,0 +1,103 @@
+package airgo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/XrayR-project/XrayR/api"
+)
+
+func TestParseAirGoNodeInfo_REALITYTransports(t *testing.T) {
+	cases := []struct {
+		name    string
+		network string
+	}{
+		{"grpc", "grpc"},
+		{"ws", "ws"},
+		{"tcp", "tcp"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+			resp := &NodeInfoResponse{
+				Protocol:       "vless",
+				Security:       "reality",
+				Network:        tc.network,
+				Port:           443,
+				Dest:           "www.example.com:443",
+				ServerNames:    []string{"www.example.com", "www2.example.com"},
+				ShortIds:       []string{"", "ab", "0123456789abcdef"},
+				PrivateKeyPool: []string{"key-a", "key-b"},
+				MinClientVer:   "1.8.0",
+				MaxClientVer:   "1.9.0",
+				MaxTimeDiff:    60,
+			}
+
+			nodeInfo, err := c.ParseAirGoNodeInfo(resp)
+			if err != nil {
+				t.Fatalf("ParseAirGoNodeInfo returned error: %v", err)
+			}
+			if !nodeInfo.EnableREALITY {
+				t.Fatalf("expected REALITY to be enabled")
+			}
+			if len(nodeInfo.REALITYConfig.ServerNames) != 2 {
+				t.Fatalf("expected 2 server names, got %v", nodeInfo.REALITYConfig.ServerNames)
+			}
+			if nodeInfo.REALITYConfig.MinClientVer != "1.8.0" {
+				t.Fatalf("expected MinClientVer to be forwarded, got %q", nodeInfo.REALITYConfig.MinClientVer)
+			}
+		})
+	}
+}
+
+func TestParseAirGoNodeInfo_PrivateKeyPoolRotates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	resp := &NodeInfoResponse{
+		Protocol:       "vless",
+		Security:       "reality",
+		Network:        "tcp",
+		Dest:           "www.example.com:443",
+		Sni:            "www.example.com",
+		PrivateKeyPool: []string{"key-a", "key-b"},
+	}
+
+	first, err := c.ParseAirGoNodeInfo(resp)
+	if err != nil {
+		t.Fatalf("ParseAirGoNodeInfo returned error: %v", err)
+	}
+	second, err := c.ParseAirGoNodeInfo(resp)
+	if err != nil {
+		t.Fatalf("ParseAirGoNodeInfo returned error: %v", err)
+	}
+	if first.REALITYConfig.PrivateKey == second.REALITYConfig.PrivateKey {
+		t.Fatalf("expected successive polls to rotate through the key pool, got %q twice",
+			first.REALITYConfig.PrivateKey)
+	}
+}
+
+func TestValidateShortIds(t *testing.T) {
+	cases := []struct {
+		name    string
+		ids     []string
+		wantErr bool
+	}{
+		{"empty allowed", []string{""}, false},
+		{"valid hex", []string{"ab", "0123456789abcdef"}, false},
+		{"odd length", []string{"abc"}, true},
+		{"too long", []string{"0123456789abcdef00"}, true},
+		{"not hex", []string{"zz"}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateShortIds(tc.ids)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error for %v", tc.ids)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("expected no error for %v, got %v", tc.ids, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package airgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/XrayR-project/XrayR/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// sendTimeout bounds a single flush's network call, derived from the
+// reporter's client context so a Close mid-flush still cancels it.
+const sendTimeout = 30 * time.Second
+
+const (
+	defaultRingSize    = 256
+	defaultFlushPeriod = 15 * time.Second
+	maxRetryAttempts   = 5
+	maxRetryBackoff    = 2 * time.Minute
+)
+
+var (
+	trafficQueuedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "xrayr",
+		Subsystem: "airgo",
+		Name:      "traffic_batches_queued_total",
+		Help:      "Total traffic/online-user batches queued for reporting.",
+	})
+	trafficDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "xrayr",
+		Subsystem: "airgo",
+		Name:      "traffic_batches_dropped_total",
+		Help:      "Total batches dropped because the in-memory ring was full.",
+	})
+	trafficRetriedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "xrayr",
+		Subsystem: "airgo",
+		Name:      "traffic_batches_retried_total",
+		Help:      "Total flush attempts that failed and were retried.",
+	})
+	trafficSpooledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "xrayr",
+		Subsystem: "airgo",
+		Name:      "traffic_batches_spooled_total",
+		Help:      "Total batches written to the on-disk overflow spool.",
+	})
+)
+
+type batchKind int
+
+const (
+	batchUserTraffic batchKind = iota
+	batchOnlineUsers
+)
+
+// trafficBatch is one unit of work the ring buffer and spool hold:
+// either a user traffic report or an online-user report, mirroring the
+// two APIClient methods it stands in for. Fields are exported so the
+// spool can round-trip a batch through encoding/json.
+type trafficBatch struct {
+	Kind           batchKind         `json:"kind"`
+	UserTraffic    []api.UserTraffic `json:"userTraffic,omitempty"`
+	OnlineUserList []api.OnlineUser  `json:"onlineUserList,omitempty"`
+}
+
+// TrafficReporter buffers UserTraffic/OnlineUser batches in a bounded
+// ring and flushes them to the panel off the caller's goroutine,
+// retrying transient HTTP failures with exponential backoff and jitter.
+// Batches that still can't be delivered are appended to a JSON-lines
+// spool file (path configured via api.Config.SpoolPath) and replayed on
+// the next flush. With no SpoolPath set, such batches are discarded; and
+// when the ring is full, the oldest queued batch is dropped.
+//
+// A BoltDB-backed spool would survive a crash mid-write more cleanly,
+// but plain JSON-lines keeps to the line-per-entry file format this
+// package already reads (see readLocalRuleList) and is good enough for an
+// overflow path that's only ever touched when the panel is down.
+type TrafficReporter struct {
+	client    *APIClient
+	spoolPath string
+
+	// retryAttempts and backoffBase are split out from the package
+	// defaults so tests can shrink them instead of waiting out real
+	// exponential backoff.
+	retryAttempts int
+	backoffBase   time.Duration
+
+	mu   sync.Mutex
+	ring []trafficBatch
+
+	flushSignal chan struct{}
+	closing     chan struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewTrafficReporter starts a background flush loop reporting through c.
+// Callers must call Close to stop the loop and give pending batches a
+// last chance to flush.
+func NewTrafficReporter(c *APIClient, apiConfig *api.Config) *TrafficReporter {
+	r := &TrafficReporter{
+		client:        c,
+		spoolPath:     apiConfig.SpoolPath,
+		retryAttempts: maxRetryAttempts,
+		backoffBase:   time.Second,
+		ring:          make([]trafficBatch, 0, defaultRingSize),
+		flushSignal:   make(chan struct{}, 1),
+		closing:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// QueueUserTraffic enqueues a traffic batch for eventual delivery. If
+// the ring is full, the oldest queued batch is dropped to make room.
+func (r *TrafficReporter) QueueUserTraffic(userTraffic []api.UserTraffic) {
+	r.enqueue(trafficBatch{Kind: batchUserTraffic, UserTraffic: userTraffic})
+}
+
+// QueueOnlineUsers enqueues an online-user batch for eventual delivery.
+func (r *TrafficReporter) QueueOnlineUsers(onlineUserList []api.OnlineUser) {
+	r.enqueue(trafficBatch{Kind: batchOnlineUsers, OnlineUserList: onlineUserList})
+}
+
+func (r *TrafficReporter) enqueue(b trafficBatch) {
+	r.mu.Lock()
+	if len(r.ring) >= defaultRingSize {
+		r.ring = r.ring[1:]
+		trafficDroppedTotal.Inc()
+	}
+	r.ring = append(r.ring, b)
+	full := len(r.ring) >= defaultRingSize
+	r.mu.Unlock()
+
+	trafficQueuedTotal.Inc()
+	if full {
+		r.requestFlush()
+	}
+}
+
+func (r *TrafficReporter) requestFlush() {
+	select {
+	case r.flushSignal <- struct{}{}:
+	default:
+	}
+}
+
+func (r *TrafficReporter) loop() {
+	defer close(r.closed)
+
+	ticker := time.NewTicker(defaultFlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-r.flushSignal:
+			r.flush()
+		case <-r.closing:
+			r.flush()
+			return
+		}
+	}
+}
+
+// flush drains the spool first, oldest data first, then the ring, so
+// batches are delivered roughly in the order they were produced.
+func (r *TrafficReporter) flush() {
+	r.drainSpool()
+
+	r.mu.Lock()
+	pending := r.ring
+	r.ring = make([]trafficBatch, 0, defaultRingSize)
+	r.mu.Unlock()
+
+	for _, b := range pending {
+		if err := r.sendWithBackoff(b); err != nil {
+			r.spool(b)
+		}
+	}
+}
+
+// sendWithBackoff retries b until it succeeds, the attempt budget runs
+// out, or the reporter is closing/the client's context is cancelled - in
+// which case it gives up on the spot instead of sleeping out the
+// remaining backoff, so Close's final flush spools the batch right away
+// rather than blocking shutdown on minutes of retry sleep.
+func (r *TrafficReporter) sendWithBackoff(b trafficBatch) error {
+	var err error
+	for attempt := 0; attempt < r.retryAttempts; attempt++ {
+		if err = r.send(b); err == nil {
+			return nil
+		}
+		trafficRetriedTotal.Inc()
+
+		backoff := time.Duration(math.Pow(2, float64(attempt))) * r.backoffBase
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+		jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+
+		select {
+		case <-time.After(backoff + jitter):
+		case <-r.closing:
+			return err
+		case <-r.client.Context().Done():
+			return err
+		}
+	}
+	return err
+}
+
+func (r *TrafficReporter) send(b trafficBatch) error {
+	ctx, cancel := context.WithTimeout(r.client.Context(), sendTimeout)
+	defer cancel()
+
+	switch b.Kind {
+	case batchUserTraffic:
+		return r.client.sendUserTraffic(ctx, &b.UserTraffic)
+	case batchOnlineUsers:
+		return r.client.sendNodeOnlineUsers(ctx, &b.OnlineUserList)
+	default:
+		return nil
+	}
+}
+
+// spool appends a batch that could not be delivered to the overflow
+// file as a JSON line.
+func (r *TrafficReporter) spool(b trafficBatch) {
+	if r.spoolPath == "" {
+		return
+	}
+
+	f, err := os.OpenFile(r.spoolPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	if _, err := f.Write(append(line, '\n')); err == nil {
+		trafficSpooledTotal.Inc()
+	}
+}
+
+// drainSpool replays everything in the spool file through the normal
+// send path, then rewrites it with whatever still failed.
+func (r *TrafficReporter) drainSpool() {
+	if r.spoolPath == "" {
+		return
+	}
+
+	f, err := os.Open(r.spoolPath)
+	if err != nil {
+		return
+	}
+
+	var remaining []trafficBatch
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var b trafficBatch
+		if err := json.Unmarshal(scanner.Bytes(), &b); err != nil {
+			continue
+		}
+		if err := r.send(b); err != nil {
+			remaining = append(remaining, b)
+		}
+	}
+	f.Close()
+
+	if len(remaining) == 0 {
+		os.Remove(r.spoolPath)
+		return
+	}
+
+	tmp, err := os.Create(r.spoolPath)
+	if err != nil {
+		return
+	}
+	defer tmp.Close()
+	for _, b := range remaining {
+		line, err := json.Marshal(b)
+		if err != nil {
+			continue
+		}
+		tmp.Write(append(line, '\n'))
+	}
+}
+
+// Close stops the flush loop, giving pending batches one last flush
+// attempt before returning.
+func (r *TrafficReporter) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closing)
+	})
+	<-r.closed
+}
@@ -0,0 +1,71 @@
+package airgo
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/XrayR-project/XrayR/api"
+)
+
+func TestTrafficReporter_SpoolsOnFailure(t *testing.T) {
+	server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	spoolPath := filepath.Join(t.TempDir(), "spool.jsonl")
+	reporter := &TrafficReporter{
+		client:        server,
+		spoolPath:     spoolPath,
+		retryAttempts: 1,
+		backoffBase:   time.Millisecond,
+		ring:          make([]trafficBatch, 0, defaultRingSize),
+		flushSignal:   make(chan struct{}, 1),
+		closing:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+
+	reporter.QueueUserTraffic([]api.UserTraffic{{UID: 1, Upload: 100, Download: 200}})
+	reporter.flush()
+
+	if _, err := os.Stat(spoolPath); err != nil {
+		t.Fatalf("expected spool file to exist after a failed flush: %v", err)
+	}
+}
+
+func TestTrafficReporter_DrainsSpoolOnNextSuccess(t *testing.T) {
+	up := false
+	server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	spoolPath := filepath.Join(t.TempDir(), "spool.jsonl")
+	reporter := &TrafficReporter{
+		client:        server,
+		spoolPath:     spoolPath,
+		retryAttempts: 1,
+		backoffBase:   time.Millisecond,
+		ring:          make([]trafficBatch, 0, defaultRingSize),
+		flushSignal:   make(chan struct{}, 1),
+		closing:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+
+	reporter.QueueUserTraffic([]api.UserTraffic{{UID: 1, Upload: 100, Download: 200}})
+	reporter.flush()
+	if _, err := os.Stat(spoolPath); err != nil {
+		t.Fatalf("expected spool file after failed flush: %v", err)
+	}
+
+	up = true
+	reporter.flush()
+	if _, err := os.Stat(spoolPath); !os.IsNotExist(err) {
+		t.Fatalf("expected spool file to be removed once drained, got err=%v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package airgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/XrayR-project/XrayR/api"
+)
+
+func TestGetUserRules(t *testing.T) {
+	rules := []UserRuleResponse{
+		{
+			Email:       "user@example.com",
+			UUID:        "11111111-1111-1111-1111-111111111111",
+			DomainList:  []string{"geosite:netflix"},
+			OutboundTag: "streaming-out",
+		},
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Etag", "v1")
+		_ = json.NewEncoder(w).Encode(rules)
+	})
+
+	userRules, err := c.GetUserRules(context.Background())
+	if err != nil {
+		t.Fatalf("GetUserRules returned error: %v", err)
+	}
+	if len(*userRules) != 1 {
+		t.Fatalf("expected 1 user rule, got %d", len(*userRules))
+	}
+	if (*userRules)[0].OutboundTag != "streaming-out" {
+		t.Fatalf("expected outbound tag to be forwarded, got %q", (*userRules)[0].OutboundTag)
+	}
+}
+
+func TestGetUserRules_NotModified(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "v1" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", "v1")
+		_ = json.NewEncoder(w).Encode([]UserRuleResponse{})
+	})
+
+	if _, err := c.GetUserRules(context.Background()); err != nil {
+		t.Fatalf("first GetUserRules returned error: %v", err)
+	}
+
+	_, err := c.GetUserRules(context.Background())
+	if err == nil || err.Error() != api.RuleNotModified {
+		t.Fatalf("expected RuleNotModified error, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import "context"
+
+// Client is the stable interface every panel backend must satisfy so the
+// controller can drive any supported panel (REST, gRPC, ...) through a
+// single abstraction instead of depending on a concrete implementation
+// such as airgo.APIClient. Every panel call takes a ctx so the controller
+// can bound a call to its poll interval and a Close can cancel whatever
+// is still in flight instead of waiting it out.
+type Client interface {
+	GetNodeInfo(ctx context.Context) (*NodeInfo, error)
+	GetUserList(ctx context.Context) (*[]UserInfo, error)
+	GetNodeRule(ctx context.Context) (*[]DetectRule, error)
+	GetUserRules(ctx context.Context) (*[]UserRule, error)
+	ReportNodeStatus(ctx context.Context, nodeStatus *NodeStatus) error
+	ReportUserTraffic(ctx context.Context, userTraffic *[]UserTraffic) error
+	ReportNodeOnlineUsers(ctx context.Context, onlineUserList *[]OnlineUser) error
+	ReportIllegal(ctx context.Context, detectResultList *[]DetectResult) error
+	Describe() ClientInfo
+	Debug()
+	// Close cancels whatever the backend has in flight and releases its
+	// underlying connection (HTTP client, gRPC channel, ...). Callers
+	// that only hold a Client built via registry.New still need a
+	// portable way to shut it down.
+	Close() error
+}
+
+// Capabilities describes the optional features a panel backend supports
+// beyond the baseline Client interface, so the controller can skip work
+// a backend can't act on instead of calling into a method that silently
+// no-ops.
+type Capabilities struct {
+	// SupportsOnlineUserReporting is false for backends that cannot push
+	// per-user online IP lists to the panel (e.g. a read-only mirror).
+	SupportsOnlineUserReporting bool
+	// SupportsIllegalReporting is false for backends without an illegal
+	// traffic reporting endpoint.
+	SupportsIllegalReporting bool
+	// SupportsStreaming is true for transports (such as gRPC) that can
+	// push traffic/online-user reports over a long-lived stream instead
+	// of one request per batch.
+	SupportsStreaming bool
+	// SupportsUserRules is false for backends without a per-user
+	// routing rule endpoint; the controller then falls back to
+	// GetNodeRule's node-wide detect rules only.
+	SupportsUserRules bool
+}
+
+// CapableClient is implemented by backends that want to advertise which
+// optional features they actually support. Backends that don't implement
+// it are assumed to support everything a plain Client exposes.
+type CapableClient interface {
+	Client
+	Capabilities() Capabilities
+}
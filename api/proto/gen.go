@@ -0,0 +1,18 @@
+// Package panel will hold the generated protobuf/gRPC code for the
+// panel gRPC transport once a consumer is added. The schema lives in
+// panel.proto; generate into this package with:
+//
+//	go generate ./api/proto
+//
+// No generated output is checked in yet, so this package is empty and
+// exports nothing - don't add a client against it until `go generate` has
+// been run and panel.pb.go/panel_grpc.pb.go are committed alongside it.
+//
+// The gRPC transport itself (a client under api/grpcpanel registering
+// against panel/registry, mirroring api/airgo) is not part of this
+// series - it's tracked as a follow-up once the generated code above is
+// checked in. Only the airgo REST backend is registered today.
+
+package panel
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative panel.proto
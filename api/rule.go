@@ -0,0 +1,18 @@
+package api
+
+// RuleNotModified mirrors NodeNotModified/UserNotModified: a backend
+// returns it from GetUserRules when the panel's ETag says nothing
+// changed, so the controller can skip re-applying routing rules.
+const RuleNotModified = "users rules not modified"
+
+// UserRule is a per-user routing directive delivered by a panel - a
+// domain/IP blocklist, or a forced outbound tag for streaming
+// unblockers - that the controller resolves into the proxy core's
+// routing rules, scoped by the user's Email/UUID.
+type UserRule struct {
+	Email       string   `json:"email"`
+	UUID        string   `json:"uuid"`
+	DomainList  []string `json:"domainList,omitempty"`
+	IPList      []string `json:"ipList,omitempty"`
+	OutboundTag string   `json:"outboundTag,omitempty"`
+}
@@ -0,0 +1,50 @@
+// Package registry lets panel backends (airgo, and future transports such
+// as gRPC) register themselves under a stable name so the controller can
+// build an api.Client purely from the configured api.Config.APIType, without
+// importing every backend package directly. Only the airgo REST backend is
+// registered today; a gRPC transport against api/proto's schema is tracked
+// as a separate follow-up, not shipped here.
+package registry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/XrayR-project/XrayR/api"
+)
+
+// Factory builds a Client from a panel configuration.
+type Factory func(c *api.Config) (api.Client, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a panel backend available under apiType. Backends call
+// this from an init() func in the package implementing them, so they
+// become available purely by being blank-imported. Register panics on a
+// duplicate apiType since that indicates two backends were compiled in
+// under the same name, a programming error rather than a runtime one.
+func Register(apiType string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := factories[apiType]; ok {
+		panic(fmt.Sprintf("registry: backend %q already registered", apiType))
+	}
+	factories[apiType] = factory
+}
+
+// New looks up the backend registered for c.APIType and constructs a
+// Client from it.
+func New(c *api.Config) (api.Client, error) {
+	mu.RLock()
+	factory, ok := factories[c.APIType]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("registry: no panel backend registered for api type %q", c.APIType)
+	}
+	return factory(c)
+}